@@ -0,0 +1,159 @@
+// Command mirror listens for TLS connections, captures each incoming
+// ClientHello, and writes it to disk (keyed by SNI) in the same
+// specconfig.Config JSON format the fingerprint client reads. This turns a
+// real browser/client connection into a replayable fixture.
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+
+	utls "github.com/refraction-networking/utls"
+
+	"go-utls-example/specconfig"
+)
+
+func main() {
+	listenAddr := flag.String("listen", ":8443", "address to listen on")
+	certFile := flag.String("cert", "", "path to TLS certificate (PEM)")
+	keyFile := flag.String("key", "", "path to TLS private key (PEM)")
+	outDir := flag.String("out", "captures", "directory to write captured ClientHello configs to, keyed by SNI")
+	flag.Parse()
+
+	if *certFile == "" || *keyFile == "" {
+		fmt.Println("Usage: go run ./cmd/mirror -cert <cert.pem> -key <key.pem> [-listen :8443] [-out captures]")
+		return
+	}
+
+	cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+	if err != nil {
+		log.Fatalf("load cert/key: %v", err)
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		log.Fatalf("create output dir: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", *listenAddr)
+	if err != nil {
+		log.Fatalf("listen: %v", err)
+	}
+	log.Printf("hello-mirror listening on %s, writing captures to %s", *listenAddr, *outDir)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Printf("accept: %v", err)
+			continue
+		}
+		go handleConn(conn, cert, *outDir)
+	}
+}
+
+func handleConn(conn net.Conn, cert tls.Certificate, outDir string) {
+	defer conn.Close()
+
+	capture := &captureConn{Conn: conn}
+	tlsConn := tls.Server(capture, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		GetConfigForClient: func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+			if err := saveCapture(capture.helloBytes(), info.ServerName, outDir); err != nil {
+				log.Printf("capture %s: %v", info.ServerName, err)
+			}
+			return nil, nil // nil means: proceed with the Config already in use
+		},
+	})
+
+	if err := tlsConn.Handshake(); err != nil {
+		log.Printf("handshake with %s: %v", conn.RemoteAddr(), err)
+	}
+}
+
+// saveCapture parses raw (a full ClientHello TLS record) into a
+// specconfig.Config and writes it to <outDir>/<sni>.json, or
+// <outDir>/_no-sni.json if the client didn't send one.
+func saveCapture(raw []byte, sni, outDir string) error {
+	if raw == nil {
+		return fmt.Errorf("no ClientHello captured")
+	}
+
+	fingerprinter := &utls.Fingerprinter{AllowBluntMimicry: true}
+	spec, err := fingerprinter.RawClientHello(raw)
+	if err != nil {
+		return fmt.Errorf("parse ClientHello: %w", err)
+	}
+
+	config, err := specconfig.ConfigFromSpec(spec)
+	if err != nil {
+		return fmt.Errorf("convert to config: %w", err)
+	}
+
+	name := sni
+	if name == "" {
+		name = "_no-sni"
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+
+	path := filepath.Join(outDir, name+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	log.Printf("captured ClientHello for %q -> %s", sni, path)
+	return nil
+}
+
+// captureConn tees every byte read from the underlying connection into a
+// buffer until a complete TLS handshake record (the ClientHello, for the
+// first flight) has been seen, so it can be fingerprinted independently of
+// the stdlib TLS handshake that continues to run on top of it.
+type captureConn struct {
+	net.Conn
+	buf  bytes.Buffer
+	full []byte
+	done bool
+}
+
+func (c *captureConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 && !c.done {
+		c.buf.Write(p[:n])
+		if hello, ok := extractTLSRecord(c.buf.Bytes()); ok {
+			c.full = hello
+			c.done = true
+		}
+	}
+	return n, err
+}
+
+func (c *captureConn) helloBytes() []byte {
+	return c.full
+}
+
+// extractTLSRecord returns the first handshake-type TLS record in buf, once
+// it has been fully received. It does not handle a ClientHello fragmented
+// across multiple TLS records.
+func extractTLSRecord(buf []byte) ([]byte, bool) {
+	const (
+		recordHeaderLen  = 5
+		handshakeContent = 0x16
+	)
+	if len(buf) < recordHeaderLen || buf[0] != handshakeContent {
+		return nil, false
+	}
+	recordLen := int(buf[3])<<8 | int(buf[4])
+	total := recordHeaderLen + recordLen
+	if len(buf) < total {
+		return nil, false
+	}
+	return buf[:total], true
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+
+	"go-utls-example/specconfig"
+)
+
+// sendH2Fingerprint sends the HTTP/2 connection preface followed by the
+// fingerprinted SETTINGS, WINDOW_UPDATE and PRIORITY frames, in that order,
+// before any request is issued.
+func sendH2Fingerprint(conn net.Conn, framer *http2.Framer, fp *specconfig.H2Fingerprint) error {
+	if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+		return fmt.Errorf("write client preface: %w", err)
+	}
+
+	settings := make([]http2.Setting, len(fp.Settings))
+	for i, s := range fp.Settings {
+		settings[i] = http2.Setting{ID: http2.SettingID(s.ID), Val: s.Value}
+	}
+	if err := framer.WriteSettings(settings...); err != nil {
+		return fmt.Errorf("write settings: %w", err)
+	}
+
+	if fp.WindowUpdateIncrement > 0 {
+		if err := framer.WriteWindowUpdate(0, fp.WindowUpdateIncrement); err != nil {
+			return fmt.Errorf("write window update: %w", err)
+		}
+	}
+
+	for _, p := range fp.Priorities {
+		err := framer.WritePriority(p.StreamID, http2.PriorityParam{
+			StreamDep: p.StreamDep,
+			Exclusive: p.Exclusive,
+			Weight:    p.Weight,
+		})
+		if err != nil {
+			return fmt.Errorf("write priority (stream %d): %w", p.StreamID, err)
+		}
+	}
+
+	return nil
+}
+
+// defaultPseudoHeaderOrder is the :method/:authority/:scheme/:path ordering
+// used when a fingerprint doesn't specify one.
+var defaultPseudoHeaderOrder = []string{":method", ":authority", ":scheme", ":path"}
+
+// defaultHeaderTableSize is the HPACK default per RFC 7541 Section 4.2, used
+// when the server's SETTINGS frame doesn't advertise a HEADER_TABLE_SIZE.
+const defaultHeaderTableSize = 4096
+
+// readServerSettings reads frames until the server's initial SETTINGS frame
+// arrives, acknowledges it, and returns the HEADER_TABLE_SIZE it advertised
+// (or the RFC 7541 default if it didn't send one).
+func readServerSettings(framer *http2.Framer) (uint32, error) {
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return 0, fmt.Errorf("read frame: %w", err)
+		}
+		settings, ok := frame.(*http2.SettingsFrame)
+		if !ok || settings.IsAck() {
+			continue
+		}
+		if err := framer.WriteSettingsAck(); err != nil {
+			return 0, fmt.Errorf("ack settings: %w", err)
+		}
+		tableSize, ok := settings.Value(http2.SettingHeaderTableSize)
+		if !ok {
+			tableSize = defaultHeaderTableSize
+		}
+		return tableSize, nil
+	}
+}
+
+// sendH2Request encodes and writes a GET request as a single HEADERS frame,
+// preserving the pseudo-header order from fp (or the default Chrome-like
+// order if unset), and applying HPACK bounded by headerTableSize, the value
+// the server actually advertised in its SETTINGS frame. RFC 7541 Section 6.3
+// requires the encoder's dynamic-table-size-update to stay within the size
+// the peer (the server) advertised, not whatever this client claims in its
+// own outbound SETTINGS (fp.Settings) - using the latter risks a
+// COMPRESSION_ERROR against a server whose table size is smaller than the
+// fingerprint's declared value.
+func sendH2Request(framer *http2.Framer, fp *specconfig.H2Fingerprint, headerTableSize uint32, streamID uint32, method, authority, scheme, path string) error {
+	order := defaultPseudoHeaderOrder
+	if len(fp.PseudoHeaderOrder) > 0 {
+		order = fp.PseudoHeaderOrder
+	}
+
+	pseudoValues := map[string]string{
+		":method":    method,
+		":authority": authority,
+		":scheme":    scheme,
+		":path":      path,
+	}
+
+	var headerBlock []byte
+	enc := hpack.NewEncoder(writerFunc(func(p []byte) (int, error) {
+		headerBlock = append(headerBlock, p...)
+		return len(p), nil
+	}))
+	enc.SetMaxDynamicTableSize(headerTableSize)
+
+	for _, name := range order {
+		val, ok := pseudoValues[name]
+		if !ok {
+			continue
+		}
+		if err := enc.WriteField(hpack.HeaderField{Name: name, Value: val}); err != nil {
+			return fmt.Errorf("encode pseudo-header %s: %w", name, err)
+		}
+	}
+	if err := enc.WriteField(hpack.HeaderField{Name: "user-agent", Value: "Mozilla/5.0"}); err != nil {
+		return err
+	}
+
+	return framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: headerBlock,
+		EndStream:     true,
+		EndHeaders:    true,
+	})
+}
+
+// readH2Response reads frames for streamID until the response is complete,
+// returning the decoded headers and the response body.
+func readH2Response(framer *http2.Framer, streamID uint32) ([]hpack.HeaderField, []byte, error) {
+	var headers []hpack.HeaderField
+	var body []byte
+	decoder := hpack.NewDecoder(4096, nil)
+
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return headers, body, fmt.Errorf("read frame: %w", err)
+		}
+
+		switch f := frame.(type) {
+		case *http2.HeadersFrame:
+			if f.StreamID != streamID {
+				continue
+			}
+			hf, err := decoder.DecodeFull(f.HeaderBlockFragment())
+			if err != nil {
+				return headers, body, fmt.Errorf("decode headers: %w", err)
+			}
+			headers = append(headers, hf...)
+			if f.StreamEnded() {
+				return headers, body, nil
+			}
+		case *http2.DataFrame:
+			if f.StreamID != streamID {
+				continue
+			}
+			body = append(body, f.Data()...)
+			if f.StreamEnded() {
+				return headers, body, nil
+			}
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				if err := framer.WriteSettingsAck(); err != nil {
+					return headers, body, fmt.Errorf("ack settings: %w", err)
+				}
+			}
+		case *http2.GoAwayFrame:
+			return headers, body, fmt.Errorf("server sent GOAWAY: %v", f.ErrCode)
+		case *http2.RSTStreamFrame:
+			if f.StreamID == streamID {
+				return headers, body, fmt.Errorf("server sent RST_STREAM: %v", f.ErrCode)
+			}
+		}
+	}
+}
+
+// writerFunc adapts a func(p []byte) (int, error) to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+var _ io.Writer = writerFunc(nil)
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tls "github.com/refraction-networking/utls"
+)
+
+// TLS extension numbers used by JA3/JA4, mirrored from the IANA registry
+// since the utls package keeps its own copies unexported.
+const (
+	extSNI                  = 0
+	extStatusRequest        = 5
+	extSupportedCurves      = 10
+	extSupportedPoints      = 11
+	extSignatureAlgorithms  = 13
+	extALPN                 = 16
+	extSCT                  = 18
+	extExtendedMasterSecret = 23
+	extSessionTicket        = 35
+	extPreSharedKey         = 41
+	extSupportedVersions    = 43
+	extCookie               = 44
+	extPSKKeyExchangeModes  = 45
+	extKeyShare             = 51
+	extRenegotiationInfo    = 0xff01
+	extPadding              = 21
+)
+
+// isGREASE reports whether id follows the 0x?a?a GREASE pattern (RFC 8701).
+func isGREASE(id uint16) bool {
+	return id&0x0f0f == 0x0a0a
+}
+
+// buildExtensionByID returns the tls.TLSExtension this tool emits by default
+// for a given extension ID, as found in a JA3/JA4 fingerprint string. curves
+// seeds the KeyShareExtension with the first non-GREASE group, matching how
+// real clients pick their preferred group for the initial key share.
+func buildExtensionByID(id uint16, curves []tls.CurveID) tls.TLSExtension {
+	if isGREASE(id) {
+		return &tls.UtlsGREASEExtension{Value: id}
+	}
+
+	switch uint16(id) {
+	case extSNI:
+		return &tls.SNIExtension{}
+	case extStatusRequest:
+		return &tls.StatusRequestExtension{}
+	case extSupportedCurves:
+		return &tls.SupportedCurvesExtension{Curves: curves}
+	case extSupportedPoints:
+		return &tls.SupportedPointsExtension{SupportedPoints: []uint8{0}}
+	case extSignatureAlgorithms:
+		return &tls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []tls.SignatureScheme{
+			tls.ECDSAWithP256AndSHA256,
+			tls.PSSWithSHA256,
+			tls.PKCS1WithSHA256,
+			tls.ECDSAWithP384AndSHA384,
+			tls.PSSWithSHA384,
+			tls.PKCS1WithSHA384,
+			tls.PSSWithSHA512,
+			tls.PKCS1WithSHA512,
+		}}
+	case extALPN:
+		return &tls.ALPNExtension{AlpnProtocols: []string{"h2", "http/1.1"}}
+	case extSCT:
+		return &tls.SCTExtension{}
+	case extExtendedMasterSecret:
+		return &tls.ExtendedMasterSecretExtension{}
+	case extSessionTicket:
+		return &tls.SessionTicketExtension{}
+	case extSupportedVersions:
+		return &tls.SupportedVersionsExtension{Versions: []uint16{tls.VersionTLS13, tls.VersionTLS12}}
+	case extCookie:
+		return &tls.CookieExtension{}
+	case extPSKKeyExchangeModes:
+		return &tls.PSKKeyExchangeModesExtension{Modes: []uint8{1}} // psk_dhe_ke, RFC 8446 Section 4.2.9
+	case extKeyShare:
+		group := tls.X25519
+		for _, c := range curves {
+			if !isGREASE(uint16(c)) {
+				group = c
+				break
+			}
+		}
+		return &tls.KeyShareExtension{KeyShares: []tls.KeyShare{{Group: group}}}
+	case extRenegotiationInfo:
+		return &tls.RenegotiationInfoExtension{Renegotiation: tls.RenegotiateOnceAsClient}
+	case extPadding:
+		return &tls.UtlsPaddingExtension{GetPaddingLen: tls.BoringPaddingStyle}
+	default:
+		// Unknown/unsupported extension ID: emit it as an empty GenericExtension
+		// so the extension list length and ordering still match the fingerprint.
+		return &tls.GenericExtension{Id: id}
+	}
+}
+
+// parseJA3 turns a JA3 string ("version,ciphers,extensions,curves,points")
+// into a ClientHelloSpec that reproduces the same five fields.
+func parseJA3(ja3 string) (*tls.ClientHelloSpec, error) {
+	fields := strings.Split(ja3, ",")
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("ja3: expected 5 comma-separated fields, got %d", len(fields))
+	}
+
+	version, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("ja3: invalid version field: %w", err)
+	}
+
+	ciphers, err := splitDashUint16(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("ja3: invalid cipher list: %w", err)
+	}
+	extIDs, err := splitDashUint16(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("ja3: invalid extension list: %w", err)
+	}
+	curveIDs, err := splitDashUint16(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("ja3: invalid curve list: %w", err)
+	}
+	pointFormats, err := splitDashUint16(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("ja3: invalid point format list: %w", err)
+	}
+
+	curves := make([]tls.CurveID, len(curveIDs))
+	for i, c := range curveIDs {
+		curves[i] = tls.CurveID(c)
+	}
+	points := make([]uint8, len(pointFormats))
+	for i, p := range pointFormats {
+		points[i] = uint8(p)
+	}
+
+	spec := &tls.ClientHelloSpec{
+		CipherSuites:       ciphers,
+		CompressionMethods: []uint8{0},
+		TLSVersMin:         tls.VersionTLS10,
+		TLSVersMax:         uint16(version),
+	}
+	if spec.TLSVersMax == 0 {
+		spec.TLSVersMax = tls.VersionTLS12
+	}
+
+	for _, id := range extIDs {
+		switch uint16(id) {
+		case extSupportedPoints:
+			spec.Extensions = append(spec.Extensions, &tls.SupportedPointsExtension{SupportedPoints: points})
+		case extSupportedCurves:
+			spec.Extensions = append(spec.Extensions, &tls.SupportedCurvesExtension{Curves: curves})
+		default:
+			spec.Extensions = append(spec.Extensions, buildExtensionByID(uint16(id), curves))
+		}
+	}
+
+	return spec, nil
+}
+
+// ja4Summary holds the fields encoded in the fixed-width JA4_a prefix, e.g.
+// "t13d1516h2" decodes to protocol=t, version=13, sni=true, 15 ciphers, 16
+// extensions, ALPN hint "h2".
+type ja4Summary struct {
+	Protocol  byte
+	Version   string
+	SNI       bool
+	CipherCnt int
+	ExtCnt    int
+	ALPNHint  string
+}
+
+func parseJA4A(a string) (*ja4Summary, error) {
+	if len(a) < 10 {
+		return nil, fmt.Errorf("ja4: prefix %q too short", a)
+	}
+	s := &ja4Summary{
+		Protocol: a[0],
+		Version:  a[1:3],
+		SNI:      a[3] == 'd',
+		ALPNHint: a[8:10],
+	}
+	cnt, err := strconv.Atoi(a[4:6])
+	if err != nil {
+		return nil, fmt.Errorf("ja4: invalid cipher count: %w", err)
+	}
+	s.CipherCnt = cnt
+	cnt, err = strconv.Atoi(a[6:8])
+	if err != nil {
+		return nil, fmt.Errorf("ja4: invalid extension count: %w", err)
+	}
+	s.ExtCnt = cnt
+	return s, nil
+}
+
+func ja4VersionToTLS(v string) uint16 {
+	switch v {
+	case "13":
+		return tls.VersionTLS13
+	case "12":
+		return tls.VersionTLS12
+	case "11":
+		return tls.VersionTLS11
+	case "10":
+		return tls.VersionTLS10
+	default:
+		return tls.VersionTLS13
+	}
+}
+
+// parseJA4 accepts the raw JA4_r/JA4_ro form ("t13d1516h2_<ciphers>_<exts>[_<sigalgs>]"
+// with 4-hex-digit, comma-separated IDs), the only JA4 variant that can be
+// reversed back into a cipher/extension/signature-algorithm list at all.
+//
+// JA4_r sorts the cipher and extension ID lists into ascending order before
+// hashing/printing them, which discards the real wire order; a JA4_r string
+// can only be replayed as that canonical sorted approximation, not
+// byte-for-byte. JA4_ro ("original order") instead keeps the lists in the
+// order they appeared on the wire, so only it can be replayed exactly.
+// original selects which one ja4 is: false re-sorts the parsed lists back
+// into canonical JA4_r order (a no-op if they're already sorted, which they
+// should be for a well-formed JA4_r string); true trusts the given order
+// verbatim as the real wire order.
+func parseJA4(ja4 string, original bool) (*tls.ClientHelloSpec, error) {
+	// SplitN keeps an optional trailing "_<sigalgs>" section inside parts[2],
+	// where it is split off from the extension list below.
+	parts := strings.SplitN(ja4, "_", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("ja4: expected at least 3 underscore-separated sections, got %d", len(parts))
+	}
+
+	summary, err := parseJA4A(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &tls.ClientHelloSpec{
+		CompressionMethods: []uint8{0},
+		TLSVersMin:         tls.VersionTLS10,
+		TLSVersMax:         ja4VersionToTLS(summary.Version),
+	}
+
+	if !strings.Contains(parts[1], ",") {
+		return nil, fmt.Errorf("ja4: %q is a truncated-hash JA4_b section; only the raw JA4_r/JA4_ro form (comma-separated hex IDs) can be replayed", parts[1])
+	}
+
+	cipherHex := strings.Split(parts[1], ",")
+	for _, h := range cipherHex {
+		if h == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(h, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("ja4: invalid cipher id %q: %w", h, err)
+		}
+		spec.CipherSuites = append(spec.CipherSuites, uint16(v))
+	}
+	if !original {
+		sort.Slice(spec.CipherSuites, func(i, j int) bool { return spec.CipherSuites[i] < spec.CipherSuites[j] })
+	}
+
+	extAndSig := strings.SplitN(parts[2], "_", 2)
+	extHex := strings.Split(extAndSig[0], ",")
+
+	var extIDs []uint16
+	for _, h := range extHex {
+		if h == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(h, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("ja4: invalid extension id %q: %w", h, err)
+		}
+		extIDs = append(extIDs, uint16(v))
+	}
+	if !original {
+		sort.Slice(extIDs, func(i, j int) bool { return extIDs[i] < extIDs[j] })
+	}
+
+	var sigAlgos []tls.SignatureScheme
+	if len(extAndSig) > 1 {
+		for _, h := range strings.Split(extAndSig[1], ",") {
+			if h == "" {
+				continue
+			}
+			v, err := strconv.ParseUint(h, 16, 16)
+			if err != nil {
+				return nil, fmt.Errorf("ja4: invalid signature algorithm %q: %w", h, err)
+			}
+			sigAlgos = append(sigAlgos, tls.SignatureScheme(v))
+		}
+	}
+
+	// JA4 doesn't encode the actual supported_groups list anywhere in the
+	// string (only whether extension 0x000a is present), so fall back to a
+	// sane default list - TLS 1.3 requires a non-empty supported_groups
+	// whenever key_share is sent (RFC 8446 Section 4.2.7).
+	curves := []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384}
+	alpn := []string{summary.ALPNHint}
+
+	for _, id := range extIDs {
+		switch id {
+		case extALPN:
+			spec.Extensions = append(spec.Extensions, &tls.ALPNExtension{AlpnProtocols: alpn})
+		case extSignatureAlgorithms:
+			if len(sigAlgos) > 0 {
+				spec.Extensions = append(spec.Extensions, &tls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: sigAlgos})
+			} else {
+				spec.Extensions = append(spec.Extensions, buildExtensionByID(id, curves))
+			}
+		default:
+			spec.Extensions = append(spec.Extensions, buildExtensionByID(id, curves))
+		}
+	}
+
+	return spec, nil
+}
+
+func splitDashUint16(s string) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, "-")
+	out := make([]uint16, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = uint16(v)
+	}
+	return out, nil
+}
@@ -2,10 +2,8 @@ package main
 
 import (
 	"bufio"
-	"crypto/ecdh"
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -14,201 +12,122 @@ import (
 	"strings"
 
 	tls "github.com/refraction-networking/utls"
-)
-
-type Config struct {
-	CipherSuites       []uint16          `json:"cipher_suites"`
-	CompressionMethods []uint8           `json:"compression_methods"`
-	Extensions         []ExtensionConfig `json:"extensions"`
-	TLSVersMin         uint16            `json:"tls_vers_min"`
-	TLSVersMax         uint16            `json:"tls_vers_max"`
-}
-
-type ExtensionConfig struct {
-	Type string          `json:"type"`
-	Data json.RawMessage `json:"data"`
-}
+	"golang.org/x/net/http2"
 
-type KeyShare struct {
-	Group   uint16 `json:"group"`
-	DataHex string `json:"data_hex"`
-}
-
-type PaddingConfig struct {
-	PaddingLen int  `json:"padding_len"`
-	WillPad    bool `json:"will_pad"`
-}
+	"go-utls-example/presets"
+	"go-utls-example/specconfig"
+	"go-utls-example/transport/shadowtls"
+)
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run main.go <config.json> [url]")
+	ja3 := flag.String("ja3", "", "JA3 fingerprint string to replay, e.g. \"771,4865-4866-4867,0-23-65281,29-23-24,0\"")
+	ja4 := flag.String("ja4", "", "JA4_r/JA4_ro fingerprint string to replay, e.g. \"t13d0303h2_1301,1302,1303_0000,000a,000b\"")
+	ja4Original := flag.Bool("ja4-original-order", false, "treat -ja4 as JA4_ro (original wire order) instead of JA4_r (canonical sorted order)")
+	preset := flag.String("preset", "", fmt.Sprintf("named browser preset to replay (%s)", strings.Join(presets.Names(), ", ")))
+	resume := flag.Bool("resume", false, "enable PSK/session-ticket resumption: read a cached session for this SNI if one exists, and save whatever ticket this handshake negotiates")
+	resumptionCache := flag.String("resumption-cache", "session-cache", "directory to persist TLS session tickets in, for PSK/session-ticket resumption")
+	flag.Parse()
+
+	args := flag.Args()
+	if *ja3 == "" && *ja4 == "" && *preset == "" && len(args) < 1 {
+		fmt.Println("Usage: go run . <config.json> [url]")
+		fmt.Println("   or: go run . -ja3 <ja3 string> [url]")
+		fmt.Println("   or: go run . -ja4 <ja4_r string> [url]")
+		fmt.Println("   or: go run . -preset <name> [overrides.json] [url]")
 		return
 	}
 
-	configFile := os.Args[1]
-	targetUrl := "https://www.google.com"
-	if len(os.Args) > 2 {
-		targetUrl = os.Args[2]
-	}
+	var spec *tls.ClientHelloSpec
+	var h2fp *specconfig.H2Fingerprint
+	var resumption *specconfig.Resumption
+	var shadow *specconfig.ShadowTLS
+	var err error
 
-	fmt.Printf("Reading config from %s\n", configFile)
-	jsonBytes, err := ioutil.ReadFile(configFile)
-	if err != nil {
-		panic(err)
-	}
+	switch {
+	case *ja3 != "":
+		fmt.Printf("Parsing JA3 fingerprint %s\n", *ja3)
+		spec, err = parseJA3(*ja3)
+		if err != nil {
+			panic(err)
+		}
+	case *ja4 != "":
+		fmt.Printf("Parsing JA4 fingerprint %s\n", *ja4)
+		spec, err = parseJA4(*ja4, *ja4Original)
+		if err != nil {
+			panic(err)
+		}
+	case *preset != "":
+		p, ok := presets.Get(*preset)
+		if !ok {
+			panic(fmt.Sprintf("unknown preset %q (available: %s)", *preset, strings.Join(presets.Names(), ", ")))
+		}
+		fmt.Printf("Using preset %s\n", *preset)
+		spec = p.Spec
+		h2fp = p.H2
 
-	var config Config
-	err = json.Unmarshal(jsonBytes, &config)
-	if err != nil {
-		panic(err)
-	}
+		if len(args) > 0 {
+			if info, statErr := os.Stat(args[0]); statErr == nil && !info.IsDir() {
+				fmt.Printf("Overriding preset %s with %s\n", *preset, args[0])
+				jsonBytes, err := ioutil.ReadFile(args[0])
+				if err != nil {
+					panic(err)
+				}
 
-	spec := &tls.ClientHelloSpec{
-		CipherSuites:       config.CipherSuites,
-		CompressionMethods: config.CompressionMethods,
-		TLSVersMin:         config.TLSVersMin,
-		TLSVersMax:         config.TLSVersMax,
-		Extensions:         make([]tls.TLSExtension, 0),
-		GetSessionID:       nil,
-	}
+				var overrides specconfig.Config
+				if err := json.Unmarshal(jsonBytes, &overrides); err != nil {
+					panic(err)
+				}
 
-	for _, extCfg := range config.Extensions {
-		var ext tls.TLSExtension
-
-		switch extCfg.Type {
-		case "SNI":
-			ext = &tls.SNIExtension{}
-		case "StatusRequest":
-			ext = &tls.StatusRequestExtension{}
-		case "SupportedCurves":
-			var curves []tls.CurveID
-			json.Unmarshal(extCfg.Data, &curves)
-            // Filter out curves we can't generate keys for (to avoid HRR failure)
-            var filteredCurves []tls.CurveID
-            for _, c := range curves {
-                // Keep GREASE
-                if (uint16(c) & 0x0f0f) == 0x0a0a {
-                    filteredCurves = append(filteredCurves, c)
-                    continue
-                }
-                // Keep standard curves
-                if c == tls.X25519 || c == tls.CurveP256 || c == tls.CurveP384 {
-                    filteredCurves = append(filteredCurves, c)
-                } else {
-                    // fmt.Printf("Dropping unsupported curve from SupportedGroups: %d\n", c)
-                }
-            }
-			ext = &tls.SupportedCurvesExtension{Curves: filteredCurves}
-		case "SupportedPoints":
-			var points []uint8
-			json.Unmarshal(extCfg.Data, &points)
-			ext = &tls.SupportedPointsExtension{SupportedPoints: points}
-		case "SignatureAlgorithms":
-			var algs []tls.SignatureScheme
-			json.Unmarshal(extCfg.Data, &algs)
-			ext = &tls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: algs}
-		case "ALPN":
-			// Force HTTP/1.1 to avoid h2 complexity for now
-			ext = &tls.ALPNExtension{AlpnProtocols: []string{"http/1.1"}}
-		case "ExtendedMasterSecret":
-			ext = &tls.ExtendedMasterSecretExtension{}
-		case "SessionTicket":
-			ext = &tls.SessionTicketExtension{}
-		case "SupportedVersions":
-			var versions []uint16
-			json.Unmarshal(extCfg.Data, &versions)
-			ext = &tls.SupportedVersionsExtension{Versions: versions}
-		case "PSKKeyExchangeModes":
-            // RFC 8446: MUST be sent if and only if "pre_shared_key" is sent.
-            // Since we don't support session tickets/PSK yet, we must skip this.
-            continue
-			/*
-			var modes []uint8
-			json.Unmarshal(extCfg.Data, &modes)
-			ext = &tls.PSKKeyExchangeModesExtension{Modes: modes}
-            */
-		case "KeyShare":
-			var shares []KeyShare
-			json.Unmarshal(extCfg.Data, &shares)
-			var keyShares []tls.KeyShare
-			for _, s := range shares {
-				curveID := tls.CurveID(s.Group)
-				var data []byte
-
-				// Check if GREASE: (val & 0x0f0f) == 0x0a0a
-				if (s.Group & 0x0f0f) == 0x0a0a {
-					data, _ = hex.DecodeString(s.DataHex)
-				} else {
-					// Generate Key Share
-					var pubKey []byte
-					
-					switch curveID {
-					case tls.X25519:
-						curve := ecdh.X25519()
-						priv, err := curve.GenerateKey(rand.Reader)
-						if err == nil {
-							pubKey = priv.PublicKey().Bytes()
-						}
-					case tls.CurveP256:
-						curve := ecdh.P256()
-						priv, err := curve.GenerateKey(rand.Reader)
-						if err == nil {
-							pubKey = priv.PublicKey().Bytes()
-						}
-					case tls.CurveP384:
-						curve := ecdh.P384()
-						priv, err := curve.GenerateKey(rand.Reader)
-						if err == nil {
-							pubKey = priv.PublicKey().Bytes()
-						}
-					default:
-						// ML-KEM or others
-						data, _ = hex.DecodeString(s.DataHex)
-                        if len(data) == 0 {
-                            // Don't send empty KeyShare
-                            continue
-                        }
-					}
-					
-					if pubKey != nil {
-						data = pubKey
-					}
+				spec, err = specconfig.ApplyOverrides(spec, overrides)
+				if err != nil {
+					panic(err)
 				}
-				
-				keyShares = append(keyShares, tls.KeyShare{Group: curveID, Data: data})
+				if overrides.H2Fingerprint != nil {
+					h2fp = overrides.H2Fingerprint
+				}
+				resumption = overrides.Resumption
+				args = args[1:]
 			}
-			ext = &tls.KeyShareExtension{KeyShares: keyShares}
-
-		case "SCT":
-			ext = &tls.SCTExtension{}
-		case "RenegotiationInfo":
-			var renegotiation uint8
-			json.Unmarshal(extCfg.Data, &renegotiation)
-			ext = &tls.RenegotiationInfoExtension{Renegotiation: tls.RenegotiationSupport(renegotiation)}
-		case "ApplicationSettings":
-			var protocols []string
-			json.Unmarshal(extCfg.Data, &protocols)
-			ext = &tls.ApplicationSettingsExtension{SupportedProtocols: protocols}
-		case "CompressCertificate":
-			var algs []tls.CertCompressionAlgo
-			json.Unmarshal(extCfg.Data, &algs)
-			ext = &tls.UtlsCompressCertExtension{Algorithms: algs}
-		case "GREASE":
-			var val uint16
-			json.Unmarshal(extCfg.Data, &val)
-			ext = &tls.UtlsGREASEExtension{Value: val, Body: nil}
-		case "Padding":
-			var pad PaddingConfig
-			json.Unmarshal(extCfg.Data, &pad)
-			ext = &tls.UtlsPaddingExtension{GetPaddingLen: tls.BoringPaddingStyle}
-		case "ECH":
-			// Skip ECH to avoid empty payload issues
-			continue
 		}
+	default:
+		configFile := args[0]
+		args = args[1:]
 
-		if ext != nil {
-			spec.Extensions = append(spec.Extensions, ext)
+		fmt.Printf("Reading config from %s\n", configFile)
+		jsonBytes, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			panic(err)
 		}
+
+		var config specconfig.Config
+		err = json.Unmarshal(jsonBytes, &config)
+		if err != nil {
+			panic(err)
+		}
+
+		spec, err = specconfig.SpecFromConfig(config)
+		if err != nil {
+			panic(err)
+		}
+		h2fp = config.H2Fingerprint
+		resumption = config.Resumption
+		shadow = config.ShadowTLS
+	}
+
+	// -resume only has an effect through tls.Config.ClientSessionCache below,
+	// which utls silently skips loading from unless the spec already carries
+	// a SessionTicketExtension or a PreSharedKeyExtension. The JSON-config
+	// path gets one for free from buildExtensions when the config has a
+	// Resumption block; JA3/JA4/preset specs never do, so add the same
+	// placeholder here whenever -resume is set, regardless of which path
+	// built spec.
+	if *resume {
+		specconfig.EnsureResumptionPlaceholder(spec)
+	}
+
+	targetUrl := "https://www.google.com"
+	if len(args) > 0 {
+		targetUrl = args[0]
 	}
 
 	host := targetUrl
@@ -222,16 +141,76 @@ func main() {
 	if !strings.Contains(host, ":") {
 		host += ":443"
 	}
-	
+
 	serverName, _, _ := net.SplitHostPort(host)
 
+	if shadow != nil {
+		fmt.Printf("Dialing %s via ShadowTLS v3 relay %s (cover SNI: %s)...\n", host, shadow.Server, shadow.CoverSNI)
+		shadowConn, err := shadowtls.Dial(shadowtls.Config{
+			Server:   shadow.Server,
+			Password: shadow.Password,
+			CoverSNI: shadow.CoverSNI,
+			Spec:     spec,
+		})
+		if err != nil {
+			panic(fmt.Sprintf("ShadowTLS dial failed: %v", err))
+		}
+		fmt.Println("✅ ShadowTLS switch confirmed, handshaking with the real target over the payload channel")
+
+		// shadowConn is the raw post-switch channel to the real target, not a
+		// TLS connection: the cover handshake terminated at the relay, so we
+		// still need a genuine TLS handshake to serverName over it before any
+		// HTTP request can be understood on the other end.
+		realTLSCfg := &tls.Config{ServerName: serverName, InsecureSkipVerify: true}
+		uConn := tls.UClient(shadowConn, realTLSCfg, tls.HelloCustom)
+		if err := uConn.ApplyPreset(spec); err != nil {
+			panic(fmt.Sprintf("ApplyPreset failed: %v", err))
+		}
+		if err := uConn.Handshake(); err != nil {
+			panic(fmt.Sprintf("Handshake failed: %v", err))
+		}
+		fmt.Println("✅ Handshake with real target successful, sending request")
+
+		req := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: Mozilla/5.0\r\nConnection: close\r\n\r\n", path, serverName)
+		uConn.Write([]byte(req))
+
+		resp, err := http.ReadResponse(bufio.NewReader(uConn), nil)
+		if err != nil {
+			fmt.Printf("Read response failed: %v\n", err)
+		} else {
+			fmt.Printf("Response Status: %s\n", resp.Status)
+			body, _ := ioutil.ReadAll(resp.Body)
+			if len(body) > 500 {
+				fmt.Printf("Body (first 500 bytes):\n%s\n...", string(body[:500]))
+			} else {
+				fmt.Printf("Body:\n%s\n", string(body))
+			}
+		}
+		return
+	}
+
 	fmt.Printf("Connecting to %s (SNI: %s)...\n", host, serverName)
 
 	configTLS := &tls.Config{
-		ServerName: serverName,
+		ServerName:         serverName,
 		InsecureSkipVerify: true,
 	}
 
+	// Resumption is opt-in: without -resume (or an explicit Resumption
+	// block), every run is a plain fingerprint replay, even though the
+	// default extension set includes SessionTicketExtension. Wiring a
+	// ClientSessionCache unconditionally would make a second run against the
+	// same SNI silently attempt real resumption (PSK extension, ticket
+	// reuse) and change the actual wire ClientHello underneath the user.
+	if *resume || resumption != nil {
+		sessionCache := newDiskSessionCache(*resumptionCache)
+		if resumption != nil {
+			sessionCache.seed(serverName, *resumption)
+		}
+		configTLS.ClientSessionCache = sessionCache
+		configTLS.OmitEmptyPsk = true
+	}
+
 	dialer := net.Dialer{}
 	conn, err := dialer.Dial("tcp", host)
 	if err != nil {
@@ -239,7 +218,7 @@ func main() {
 	}
 
 	uConn := tls.UClient(conn, configTLS, tls.HelloCustom)
-	
+
 	err = uConn.ApplyPreset(spec)
 	if err != nil {
 		panic(fmt.Sprintf("ApplyPreset failed: %v", err))
@@ -252,10 +231,24 @@ func main() {
 
 	state := uConn.ConnectionState()
 	fmt.Printf("✅ Handshake successful! Protocol: %s\n", state.NegotiatedProtocol)
-	
+
+	if state.NegotiatedProtocol == "h2" {
+		if h2fp == nil {
+			h2fp = &specconfig.H2Fingerprint{Settings: []specconfig.H2Setting{
+				{ID: uint16(http2.SettingHeaderTableSize), Value: 65536},
+				{ID: uint16(http2.SettingInitialWindowSize), Value: 6291456},
+				{ID: uint16(http2.SettingMaxHeaderListSize), Value: 262144},
+			}}
+		}
+		if err := doH2Request(uConn, h2fp, serverName, path); err != nil {
+			panic(fmt.Sprintf("h2 request failed: %v", err))
+		}
+		return
+	}
+
 	req := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: Mozilla/5.0\r\nConnection: close\r\n\r\n", path, serverName)
 	uConn.Write([]byte(req))
-	
+
 	resp, err := http.ReadResponse(bufio.NewReader(uConn), nil)
 	if err != nil {
 		fmt.Printf("Read response failed: %v\n", err)
@@ -269,3 +262,42 @@ func main() {
 		}
 	}
 }
+
+// doH2Request replays fp's Akamai HTTP/2 fingerprint over uConn and issues a
+// single GET request for path, printing the response status and body.
+func doH2Request(uConn *tls.UConn, fp *specconfig.H2Fingerprint, authority, path string) error {
+	framer := http2.NewFramer(uConn, uConn)
+
+	if err := sendH2Fingerprint(uConn, framer, fp); err != nil {
+		return fmt.Errorf("send fingerprint: %w", err)
+	}
+
+	headerTableSize, err := readServerSettings(framer)
+	if err != nil {
+		return fmt.Errorf("read server settings: %w", err)
+	}
+
+	const streamID = 1
+	if err := sendH2Request(framer, fp, headerTableSize, streamID, "GET", authority, "https", path); err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+
+	headers, body, err := readH2Response(framer, streamID)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	status := ""
+	for _, h := range headers {
+		if h.Name == ":status" {
+			status = h.Value
+		}
+	}
+	fmt.Printf("Response Status: %s\n", status)
+	if len(body) > 500 {
+		fmt.Printf("Body (first 500 bytes):\n%s\n...", string(body[:500]))
+	} else {
+		fmt.Printf("Body:\n%s\n", string(body))
+	}
+	return nil
+}
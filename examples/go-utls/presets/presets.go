@@ -0,0 +1,289 @@
+// Package presets ships built-in ClientHelloSpec tables for current browser
+// releases, similar in shape to utls's own utlsIdToSpec but kept independent
+// of it so this tool can track browsers before upstream ships a named
+// tls.ClientHelloID for them. Each preset is a fresh *tls.ClientHelloSpec (and,
+// where the browser negotiates HTTP/2, a matching Akamai fingerprint) built
+// from scratch on every call, since ApplyPreset mutates KeyShare/GREASE
+// fields in place and a shared package-level spec would leak state between
+// connections.
+package presets
+
+import (
+	"sort"
+
+	tls "github.com/refraction-networking/utls"
+
+	"go-utls-example/specconfig"
+)
+
+// Preset bundles the TLS ClientHelloSpec and, for browsers that negotiate
+// h2, the HTTP/2 (Akamai) fingerprint to replay alongside it.
+type Preset struct {
+	Spec *tls.ClientHelloSpec
+	H2   *specconfig.H2Fingerprint
+}
+
+var byName = map[string]func() Preset{
+	"chrome131":  chrome131,
+	"firefox133": firefox133,
+	"safari18":   safari18,
+	"ios18":      ios18,
+}
+
+// Get returns the named preset, or false if name isn't one of Names().
+func Get(name string) (Preset, bool) {
+	build, ok := byName[name]
+	if !ok {
+		return Preset{}, false
+	}
+	return build(), true
+}
+
+// Names returns the available preset names, sorted.
+func Names() []string {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func chrome131() Preset {
+	return Preset{
+		Spec: &tls.ClientHelloSpec{
+			CipherSuites: []uint16{
+				tls.GREASE_PLACEHOLDER,
+				tls.TLS_AES_128_GCM_SHA256,
+				tls.TLS_AES_256_GCM_SHA384,
+				tls.TLS_CHACHA20_POLY1305_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+				tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			},
+			CompressionMethods: []uint8{0x00},
+			Extensions: tls.ShuffleChromeTLSExtensions([]tls.TLSExtension{
+				&tls.UtlsGREASEExtension{},
+				&tls.SNIExtension{},
+				&tls.ExtendedMasterSecretExtension{},
+				&tls.RenegotiationInfoExtension{Renegotiation: tls.RenegotiateOnceAsClient},
+				&tls.SupportedCurvesExtension{Curves: []tls.CurveID{
+					tls.GREASE_PLACEHOLDER,
+					tls.X25519MLKEM768,
+					tls.X25519,
+					tls.CurveP256,
+					tls.CurveP384,
+				}},
+				&tls.SupportedPointsExtension{SupportedPoints: []uint8{0x00}},
+				&tls.SessionTicketExtension{},
+				&tls.ALPNExtension{AlpnProtocols: []string{"h2", "http/1.1"}},
+				&tls.StatusRequestExtension{},
+				&tls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []tls.SignatureScheme{
+					tls.ECDSAWithP256AndSHA256,
+					tls.PSSWithSHA256,
+					tls.PKCS1WithSHA256,
+					tls.ECDSAWithP384AndSHA384,
+					tls.PSSWithSHA384,
+					tls.PKCS1WithSHA384,
+					tls.PSSWithSHA512,
+					tls.PKCS1WithSHA512,
+				}},
+				&tls.SCTExtension{},
+				&tls.KeyShareExtension{KeyShares: []tls.KeyShare{
+					{Group: tls.CurveID(tls.GREASE_PLACEHOLDER), Data: []byte{0}},
+					{Group: tls.X25519MLKEM768},
+					{Group: tls.X25519},
+				}},
+				&tls.PSKKeyExchangeModesExtension{Modes: []uint8{tls.PskModeDHE}},
+				&tls.SupportedVersionsExtension{Versions: []uint16{
+					tls.GREASE_PLACEHOLDER,
+					tls.VersionTLS13,
+					tls.VersionTLS12,
+				}},
+				&tls.UtlsCompressCertExtension{Algorithms: []tls.CertCompressionAlgo{tls.CertCompressionBrotli}},
+				&tls.ApplicationSettingsExtensionNew{SupportedProtocols: []string{"h2"}},
+				&tls.UtlsGREASEExtension{},
+			}),
+		},
+		H2: &specconfig.H2Fingerprint{
+			Settings: []specconfig.H2Setting{
+				{ID: 1, Value: 65536},   // HEADER_TABLE_SIZE
+				{ID: 2, Value: 0},       // ENABLE_PUSH
+				{ID: 4, Value: 6291456}, // INITIAL_WINDOW_SIZE
+				{ID: 6, Value: 262144},  // MAX_HEADER_LIST_SIZE
+			},
+			WindowUpdateIncrement: 15663105,
+			PseudoHeaderOrder:     []string{":method", ":authority", ":scheme", ":path"},
+		},
+	}
+}
+
+func firefox133() Preset {
+	return Preset{
+		Spec: &tls.ClientHelloSpec{
+			TLSVersMin: tls.VersionTLS12,
+			TLSVersMax: tls.VersionTLS13,
+			CipherSuites: []uint16{
+				tls.TLS_AES_128_GCM_SHA256,
+				tls.TLS_CHACHA20_POLY1305_SHA256,
+				tls.TLS_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			},
+			CompressionMethods: []uint8{0x00},
+			Extensions: []tls.TLSExtension{
+				&tls.SNIExtension{},
+				&tls.ExtendedMasterSecretExtension{},
+				&tls.RenegotiationInfoExtension{Renegotiation: tls.RenegotiateOnceAsClient},
+				&tls.SupportedCurvesExtension{Curves: []tls.CurveID{
+					tls.X25519MLKEM768,
+					tls.X25519,
+					tls.CurveP256,
+					tls.CurveP384,
+					tls.CurveP521,
+				}},
+				&tls.SupportedPointsExtension{SupportedPoints: []uint8{0x00}},
+				&tls.SessionTicketExtension{},
+				&tls.ALPNExtension{AlpnProtocols: []string{"h2", "http/1.1"}},
+				&tls.StatusRequestExtension{},
+				&tls.KeyShareExtension{KeyShares: []tls.KeyShare{
+					{Group: tls.X25519MLKEM768},
+					{Group: tls.X25519},
+					{Group: tls.CurveP256},
+				}},
+				&tls.SupportedVersionsExtension{Versions: []uint16{tls.VersionTLS13, tls.VersionTLS12}},
+				&tls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []tls.SignatureScheme{
+					tls.ECDSAWithP256AndSHA256,
+					tls.ECDSAWithP384AndSHA384,
+					tls.ECDSAWithP521AndSHA512,
+					tls.PSSWithSHA256,
+					tls.PSSWithSHA384,
+					tls.PSSWithSHA512,
+					tls.PKCS1WithSHA256,
+					tls.PKCS1WithSHA384,
+					tls.PKCS1WithSHA512,
+					tls.ECDSAWithSHA1,
+					tls.PKCS1WithSHA1,
+				}},
+				&tls.PSKKeyExchangeModesExtension{Modes: []uint8{tls.PskModeDHE}},
+			},
+		},
+		H2: &specconfig.H2Fingerprint{
+			Settings: []specconfig.H2Setting{
+				{ID: 1, Value: 65536},
+				{ID: 4, Value: 131072},
+				{ID: 5, Value: 16384},
+			},
+			WindowUpdateIncrement: 12517377,
+			PseudoHeaderOrder:     []string{":method", ":path", ":authority", ":scheme"},
+		},
+	}
+}
+
+func safari18() Preset {
+	return Preset{
+		Spec: &tls.ClientHelloSpec{
+			TLSVersMin: tls.VersionTLS10,
+			TLSVersMax: tls.VersionTLS13,
+			CipherSuites: []uint16{
+				tls.GREASE_PLACEHOLDER,
+				tls.TLS_AES_128_GCM_SHA256,
+				tls.TLS_AES_256_GCM_SHA384,
+				tls.TLS_CHACHA20_POLY1305_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+				tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			},
+			CompressionMethods: []uint8{0x00},
+			Extensions: []tls.TLSExtension{
+				&tls.UtlsGREASEExtension{},
+				&tls.SNIExtension{},
+				&tls.ExtendedMasterSecretExtension{},
+				&tls.RenegotiationInfoExtension{Renegotiation: tls.RenegotiateOnceAsClient},
+				&tls.SupportedCurvesExtension{Curves: []tls.CurveID{
+					tls.GREASE_PLACEHOLDER,
+					tls.X25519MLKEM768,
+					tls.X25519,
+					tls.CurveP256,
+					tls.CurveP384,
+					tls.CurveP521,
+				}},
+				&tls.SupportedPointsExtension{SupportedPoints: []uint8{0x00}},
+				&tls.ALPNExtension{AlpnProtocols: []string{"h2", "http/1.1"}},
+				&tls.StatusRequestExtension{},
+				&tls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []tls.SignatureScheme{
+					tls.ECDSAWithP256AndSHA256,
+					tls.PSSWithSHA256,
+					tls.PKCS1WithSHA256,
+					tls.ECDSAWithP384AndSHA384,
+					tls.ECDSAWithSHA1,
+					tls.PSSWithSHA384,
+					tls.PKCS1WithSHA384,
+					tls.PSSWithSHA512,
+					tls.PKCS1WithSHA512,
+					tls.PKCS1WithSHA1,
+				}},
+				&tls.SCTExtension{},
+				&tls.KeyShareExtension{KeyShares: []tls.KeyShare{
+					{Group: tls.CurveID(tls.GREASE_PLACEHOLDER), Data: []byte{0}},
+					{Group: tls.X25519MLKEM768},
+					{Group: tls.X25519},
+				}},
+				&tls.PSKKeyExchangeModesExtension{Modes: []uint8{tls.PskModeDHE}},
+				&tls.SupportedVersionsExtension{Versions: []uint16{
+					tls.GREASE_PLACEHOLDER,
+					tls.VersionTLS13,
+					tls.VersionTLS12,
+					tls.VersionTLS11,
+					tls.VersionTLS10,
+				}},
+				&tls.UtlsCompressCertExtension{Algorithms: []tls.CertCompressionAlgo{tls.CertCompressionZlib}},
+				&tls.UtlsGREASEExtension{},
+				&tls.UtlsPaddingExtension{GetPaddingLen: tls.BoringPaddingStyle},
+			},
+		},
+		H2: &specconfig.H2Fingerprint{
+			Settings: []specconfig.H2Setting{
+				{ID: 4, Value: 2097152},
+				{ID: 3, Value: 100},
+			},
+			PseudoHeaderOrder: []string{":method", ":scheme", ":path", ":authority"},
+		},
+	}
+}
+
+func ios18() Preset {
+	preset := safari18()
+	// Mobile Safari shares its desktop sibling's extension layout, but ships
+	// with brotli cert compression instead of zlib and a smaller
+	// INITIAL_WINDOW_SIZE setting to match iOS's HTTP/2 stack.
+	for _, ext := range preset.Spec.Extensions {
+		if compress, ok := ext.(*tls.UtlsCompressCertExtension); ok {
+			compress.Algorithms = []tls.CertCompressionAlgo{tls.CertCompressionBrotli}
+		}
+	}
+	preset.H2 = &specconfig.H2Fingerprint{
+		Settings: []specconfig.H2Setting{
+			{ID: 4, Value: 1048576},
+			{ID: 3, Value: 100},
+		},
+		PseudoHeaderOrder: []string{":method", ":scheme", ":path", ":authority"},
+	}
+	return preset
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tls "github.com/refraction-networking/utls"
+
+	"go-utls-example/specconfig"
+)
+
+var errNoSession = errors.New("resumption: no session to persist")
+
+// diskSessionCache is a tls.ClientSessionCache that persists each session to
+// <dir>/<sessionKey>.json (sessionKey is the SNI utls resumed against) so a
+// ticket handed out by one run can be replayed by the next.
+type diskSessionCache struct {
+	dir string
+}
+
+func newDiskSessionCache(dir string) *diskSessionCache {
+	os.MkdirAll(dir, 0o755)
+	return &diskSessionCache{dir: dir}
+}
+
+func (c *diskSessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	data, err := os.ReadFile(c.path(sessionKey))
+	if err != nil {
+		return nil, false
+	}
+
+	var res specconfig.Resumption
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, false
+	}
+
+	cs, err := sessionStateFromResumption(res)
+	if err != nil {
+		return nil, false
+	}
+	return cs, true
+}
+
+func (c *diskSessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	if cs == nil {
+		os.Remove(c.path(sessionKey))
+		return
+	}
+
+	res, err := resumptionFromSessionState(cs)
+	if err != nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(c.path(sessionKey), data, 0o600)
+}
+
+// seed pre-populates the cache for sessionKey with a Resumption loaded from a
+// config file, so a freshly started process can resume a session it didn't
+// negotiate itself. hello-mirror captures never carry a Resumption block, since
+// a ClientHello does not contain the session state.
+func (c *diskSessionCache) seed(sessionKey string, res specconfig.Resumption) {
+	cs, err := sessionStateFromResumption(res)
+	if err != nil {
+		return
+	}
+	c.Put(sessionKey, cs)
+}
+
+func (c *diskSessionCache) path(sessionKey string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, sessionKey)
+	return filepath.Join(c.dir, safe+".json")
+}
+
+func sessionStateFromResumption(res specconfig.Resumption) (*tls.ClientSessionState, error) {
+	ticket, err := hex.DecodeString(res.TicketHex)
+	if err != nil {
+		return nil, err
+	}
+	stateBytes, err := hex.DecodeString(res.StateHex)
+	if err != nil {
+		return nil, err
+	}
+	state, err := tls.ParseSessionState(stateBytes)
+	if err != nil {
+		return nil, err
+	}
+	return tls.NewResumptionState(ticket, state)
+}
+
+func resumptionFromSessionState(cs *tls.ClientSessionState) (specconfig.Resumption, error) {
+	ticket, state, err := cs.ResumptionState()
+	if err != nil {
+		return specconfig.Resumption{}, err
+	}
+	if state == nil {
+		return specconfig.Resumption{}, errNoSession
+	}
+	stateBytes, err := state.Bytes()
+	if err != nil {
+		return specconfig.Resumption{}, err
+	}
+	return specconfig.Resumption{
+		TicketHex: hex.EncodeToString(ticket),
+		StateHex:  hex.EncodeToString(stateBytes),
+	}, nil
+}
@@ -0,0 +1,513 @@
+// Package specconfig holds the verbose JSON ClientHello schema shared by the
+// fingerprint client (examples/go-utls) and the hello-mirror capture server
+// (examples/go-utls/cmd/mirror), so a captured ClientHello can be written to
+// disk and fed straight back into the client unchanged.
+package specconfig
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	tls "github.com/refraction-networking/utls"
+)
+
+type Config struct {
+	CipherSuites       []uint16          `json:"cipher_suites"`
+	CompressionMethods []uint8           `json:"compression_methods"`
+	Extensions         []ExtensionConfig `json:"extensions"`
+	TLSVersMin         uint16            `json:"tls_vers_min"`
+	TLSVersMax         uint16            `json:"tls_vers_max"`
+	H2Fingerprint      *H2Fingerprint    `json:"h2_fingerprint,omitempty"`
+	Resumption         *Resumption       `json:"resumption,omitempty"`
+	ShadowTLS          *ShadowTLS        `json:"shadowtls,omitempty"`
+}
+
+// ShadowTLS configures a ShadowTLS v3 relay to dial through instead of
+// connecting to the target directly: Server is the relay's address, CoverSNI
+// is the innocuous site the relay forwards the handshake to, and Password is
+// the secret that authenticates the relay to us and the post-handshake
+// switch to us. See transport/shadowtls for the client implementation.
+type ShadowTLS struct {
+	Server   string `json:"server"`
+	Password string `json:"password"`
+	CoverSNI string `json:"cover_sni"`
+}
+
+// Resumption carries a previously issued TLS 1.3 session - the ticket utls
+// received plus the PSK secret, cipher suite, and obfuscated ticket age it
+// negotiated that session with - so a later run can offer it in a fresh
+// PreSharedKeyExtension instead of performing a full handshake. StateHex is
+// the tls.SessionState.Bytes() encoding, which is utls's own format for
+// persisting that session state (everything above except the ticket itself,
+// which TicketHex holds); utls doesn't expose a constructor to
+// assemble a SessionState from the individual fields, only the Bytes/
+// ParseSessionState round trip, so that's what's stored here rather than
+// duplicating its private encoding.
+type Resumption struct {
+	TicketHex string `json:"ticket_hex"`
+	StateHex  string `json:"state_hex"`
+}
+
+type ExtensionConfig struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type KeyShare struct {
+	Group   uint16 `json:"group"`
+	DataHex string `json:"data_hex"`
+}
+
+type PaddingConfig struct {
+	PaddingLen int  `json:"padding_len"`
+	WillPad    bool `json:"will_pad"`
+}
+
+// CookieConfig carries the HelloRetryRequest cookie (extension 44) a server
+// asked the client to echo back on its second ClientHello.
+type CookieConfig struct {
+	DataHex string `json:"data_hex"`
+}
+
+// PSKConfig carries a captured PreSharedKey extension's identities and
+// binders, so a replayed hello can reproduce the same wire bytes even though
+// (without the original session secret) the binders can't be freshly
+// computed. Live resumption instead goes through Config.Resumption, where
+// utls derives a fresh, valid binder.
+type PSKConfig struct {
+	Identities []PSKIdentity `json:"identities"`
+	BindersHex []string      `json:"binders_hex"`
+}
+
+type PSKIdentity struct {
+	LabelHex            string `json:"label_hex"`
+	ObfuscatedTicketAge uint32 `json:"obfuscated_ticket_age"`
+}
+
+// GenericExtensionConfig round-trips an extension this tool doesn't parse
+// into a typed struct, keyed by its raw extension ID.
+type GenericExtensionConfig struct {
+	ID      uint16 `json:"id"`
+	DataHex string `json:"data_hex"`
+}
+
+// H2Fingerprint describes the Akamai HTTP/2 fingerprint: the ordered SETTINGS
+// frame values, the connection-level WINDOW_UPDATE increment, the PRIORITY
+// frames sent right after the preface, and the pseudo-header order used on
+// the request's HEADERS frame.
+type H2Fingerprint struct {
+	Settings              []H2Setting  `json:"settings"`
+	WindowUpdateIncrement uint32       `json:"window_update_increment"`
+	Priorities            []H2Priority `json:"priorities"`
+	PseudoHeaderOrder     []string     `json:"pseudo_header_order"`
+}
+
+type H2Setting struct {
+	ID    uint16 `json:"id"`
+	Value uint32 `json:"value"`
+}
+
+type H2Priority struct {
+	StreamID  uint32 `json:"stream_id"`
+	StreamDep uint32 `json:"stream_dep"`
+	Weight    uint8  `json:"weight"`
+	Exclusive bool   `json:"exclusive"`
+}
+
+// SpecFromConfig builds a ClientHelloSpec from the verbose JSON Config format.
+func SpecFromConfig(config Config) (*tls.ClientHelloSpec, error) {
+	extensions, err := buildExtensions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.ClientHelloSpec{
+		CipherSuites:       config.CipherSuites,
+		CompressionMethods: config.CompressionMethods,
+		TLSVersMin:         config.TLSVersMin,
+		TLSVersMax:         config.TLSVersMax,
+		Extensions:         extensions,
+		GetSessionID:       nil,
+	}, nil
+}
+
+// ApplyOverrides layers the non-empty fields of overrides onto base (typically
+// a named preset), so a small JSON file can tweak just the fields a user
+// cares about instead of repeating the whole ClientHello.
+func ApplyOverrides(base *tls.ClientHelloSpec, overrides Config) (*tls.ClientHelloSpec, error) {
+	spec := *base
+
+	if len(overrides.CipherSuites) > 0 {
+		spec.CipherSuites = overrides.CipherSuites
+	}
+	if len(overrides.CompressionMethods) > 0 {
+		spec.CompressionMethods = overrides.CompressionMethods
+	}
+	if overrides.TLSVersMin != 0 {
+		spec.TLSVersMin = overrides.TLSVersMin
+	}
+	if overrides.TLSVersMax != 0 {
+		spec.TLSVersMax = overrides.TLSVersMax
+	}
+	if len(overrides.Extensions) > 0 {
+		extensions, err := buildExtensions(overrides)
+		if err != nil {
+			return nil, err
+		}
+		spec.Extensions = extensions
+	} else if overrides.Resumption != nil {
+		// Keep base's extensions and only add the resumption placeholder.
+		extensions := append([]tls.TLSExtension(nil), spec.Extensions...)
+		spec.Extensions = ensureResumptionPlaceholder(extensions)
+	}
+
+	return &spec, nil
+}
+
+// buildExtensions turns config's Extensions list (plus its Resumption block,
+// if any) into the TLSExtension values a ClientHelloSpec carries.
+func buildExtensions(config Config) ([]tls.TLSExtension, error) {
+	extensions := make([]tls.TLSExtension, 0, len(config.Extensions))
+
+	for _, extCfg := range config.Extensions {
+		var ext tls.TLSExtension
+
+		switch extCfg.Type {
+		case "SNI":
+			ext = &tls.SNIExtension{}
+		case "StatusRequest":
+			ext = &tls.StatusRequestExtension{}
+		case "SupportedCurves":
+			var curves []tls.CurveID
+			json.Unmarshal(extCfg.Data, &curves)
+			// Filter out curves we can't generate keys for (to avoid HRR failure)
+			var filteredCurves []tls.CurveID
+			for _, c := range curves {
+				// Keep GREASE
+				if (uint16(c) & 0x0f0f) == 0x0a0a {
+					filteredCurves = append(filteredCurves, c)
+					continue
+				}
+				// Keep standard curves, plus the hybrid post-quantum groups we
+				// can generate fresh key shares for (see keySharesFromConfig).
+				if c == tls.X25519 || c == tls.CurveP256 || c == tls.CurveP384 ||
+					c == tls.X25519MLKEM768 || c == tls.X25519Kyber768Draft00 {
+					filteredCurves = append(filteredCurves, c)
+				} else {
+					// fmt.Printf("Dropping unsupported curve from SupportedGroups: %d\n", c)
+				}
+			}
+			ext = &tls.SupportedCurvesExtension{Curves: filteredCurves}
+		case "SupportedPoints":
+			var points []uint8
+			json.Unmarshal(extCfg.Data, &points)
+			ext = &tls.SupportedPointsExtension{SupportedPoints: points}
+		case "SignatureAlgorithms":
+			var algs []tls.SignatureScheme
+			json.Unmarshal(extCfg.Data, &algs)
+			ext = &tls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: algs}
+		case "ALPN":
+			var protocols []string
+			json.Unmarshal(extCfg.Data, &protocols)
+			if len(protocols) == 0 {
+				protocols = []string{"http/1.1"}
+			}
+			ext = &tls.ALPNExtension{AlpnProtocols: protocols}
+		case "ExtendedMasterSecret":
+			ext = &tls.ExtendedMasterSecretExtension{}
+		case "SessionTicket":
+			ext = &tls.SessionTicketExtension{}
+		case "SupportedVersions":
+			var versions []uint16
+			json.Unmarshal(extCfg.Data, &versions)
+			ext = &tls.SupportedVersionsExtension{Versions: versions}
+		case "PSKKeyExchangeModes":
+			var modes []uint8
+			json.Unmarshal(extCfg.Data, &modes)
+			ext = &tls.PSKKeyExchangeModesExtension{Modes: modes}
+		case "KeyShare":
+			var shares []KeyShare
+			json.Unmarshal(extCfg.Data, &shares)
+			keyShares, err := keySharesFromConfig(shares)
+			if err != nil {
+				return nil, err
+			}
+			ext = &tls.KeyShareExtension{KeyShares: keyShares}
+		case "SCT":
+			ext = &tls.SCTExtension{}
+		case "RenegotiationInfo":
+			var renegotiation uint8
+			json.Unmarshal(extCfg.Data, &renegotiation)
+			ext = &tls.RenegotiationInfoExtension{Renegotiation: tls.RenegotiationSupport(renegotiation)}
+		case "ApplicationSettings":
+			var protocols []string
+			json.Unmarshal(extCfg.Data, &protocols)
+			ext = &tls.ApplicationSettingsExtension{SupportedProtocols: protocols}
+		case "CompressCertificate":
+			var algs []tls.CertCompressionAlgo
+			json.Unmarshal(extCfg.Data, &algs)
+			ext = &tls.UtlsCompressCertExtension{Algorithms: algs}
+		case "GREASE":
+			var val uint16
+			json.Unmarshal(extCfg.Data, &val)
+			ext = &tls.UtlsGREASEExtension{Value: val, Body: nil}
+		case "Padding":
+			var pad PaddingConfig
+			json.Unmarshal(extCfg.Data, &pad)
+			// Use the captured length/willPad verbatim rather than
+			// recomputing BoringPaddingStyle, so a captured hello
+			// reproduces the same padding it was recorded with.
+			paddingLen, willPad := pad.PaddingLen, pad.WillPad
+			ext = &tls.UtlsPaddingExtension{
+				PaddingLen: paddingLen,
+				WillPad:    willPad,
+				GetPaddingLen: func(int) (int, bool) {
+					return paddingLen, willPad
+				},
+			}
+		case "Cookie":
+			var cookie CookieConfig
+			json.Unmarshal(extCfg.Data, &cookie)
+			data, _ := hex.DecodeString(cookie.DataHex)
+			ext = &tls.CookieExtension{Cookie: data}
+		case "PreSharedKey":
+			// A live resumption (config.Resumption) is handled after the
+			// loop instead, where utls computes a fresh, valid binder over
+			// the ClientHello being built. This case only fires for a
+			// captured hello replayed without a Resumption block: the
+			// binders are stale and won't authenticate a real session, but
+			// reproducing them keeps the wire bytes identical for
+			// fingerprinting purposes.
+			if config.Resumption != nil {
+				continue
+			}
+			var psk PSKConfig
+			json.Unmarshal(extCfg.Data, &psk)
+			identities := make([]tls.PskIdentity, len(psk.Identities))
+			for i, id := range psk.Identities {
+				label, _ := hex.DecodeString(id.LabelHex)
+				identities[i] = tls.PskIdentity{Label: label, ObfuscatedTicketAge: id.ObfuscatedTicketAge}
+			}
+			binders := make([][]byte, len(psk.BindersHex))
+			for i, b := range psk.BindersHex {
+				binders[i], _ = hex.DecodeString(b)
+			}
+			ext = &tls.UtlsPreSharedKeyExtension{
+				PreSharedKeyCommon: tls.PreSharedKeyCommon{Identities: identities, Binders: binders},
+			}
+		case "ECH":
+			// Skip ECH to avoid empty payload issues
+			continue
+		case "Generic":
+			var g GenericExtensionConfig
+			json.Unmarshal(extCfg.Data, &g)
+			data, _ := hex.DecodeString(g.DataHex)
+			ext = &tls.GenericExtension{Id: g.ID, Data: data}
+		}
+
+		if ext != nil {
+			extensions = append(extensions, ext)
+		}
+	}
+
+	if config.Resumption != nil {
+		extensions = ensureResumptionPlaceholder(extensions)
+	}
+
+	return extensions, nil
+}
+
+// pskModeDHEKE is psk_dhe_ke from RFC 8446 Section 4.2.9, the only mode utls
+// implements key derivation for.
+const pskModeDHEKE = 1
+
+func hasPSKKeyExchangeModes(extensions []tls.TLSExtension) bool {
+	for _, e := range extensions {
+		if _, ok := e.(*tls.PSKKeyExchangeModesExtension); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPreSharedKey(extensions []tls.TLSExtension) bool {
+	for _, e := range extensions {
+		if _, ok := e.(*tls.UtlsPreSharedKeyExtension); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ensureResumptionPlaceholder(extensions []tls.TLSExtension) []tls.TLSExtension {
+	if !hasPSKKeyExchangeModes(extensions) {
+		extensions = append(extensions, &tls.PSKKeyExchangeModesExtension{Modes: []uint8{pskModeDHEKE}})
+	}
+	if !hasPreSharedKey(extensions) {
+		extensions = append(extensions, &tls.UtlsPreSharedKeyExtension{})
+	}
+	return extensions
+}
+
+// EnsureResumptionPlaceholder appends the same PSKKeyExchangeModes/PreSharedKey
+// placeholder buildExtensions adds for a JSON Resumption block (skipping
+// either one already present in spec.Extensions). The JA3/JA4/preset spec
+// builders never go through buildExtensions, so without this, wiring a
+// tls.Config.ClientSessionCache onto one of their specs is a silent no-op:
+// utls's session controller only calls loadSession() when the ClientHello
+// carries a SessionTicketExtension or a PreSharedKeyExtension.
+func EnsureResumptionPlaceholder(spec *tls.ClientHelloSpec) {
+	spec.Extensions = ensureResumptionPlaceholder(spec.Extensions)
+}
+
+// keySharesFromConfig generates a fresh key share for every non-GREASE group,
+// falling back to the captured DataHex when the group can't be freshly
+// generated (e.g. a group this tool doesn't know how to mimic).
+func keySharesFromConfig(shares []KeyShare) ([]tls.KeyShare, error) {
+	var keyShares []tls.KeyShare
+	for _, s := range shares {
+		curveID := tls.CurveID(s.Group)
+		var data []byte
+
+		if (s.Group & 0x0f0f) == 0x0a0a {
+			data, _ = hex.DecodeString(s.DataHex)
+			keyShares = append(keyShares, tls.KeyShare{Group: curveID, Data: data})
+			continue
+		}
+
+		switch curveID {
+		case tls.X25519MLKEM768, tls.X25519Kyber768Draft00:
+			// Leave Data empty: uConn.ApplyPreset generates a fresh hybrid
+			// X25519+ML-KEM key pair for these groups itself (see u_parrots.go)
+			// and stores the private halves on the connection so the ServerHello
+			// share can be decapsulated when the handshake finishes. A captured
+			// DataHex payload is a stale public key with no matching private
+			// key, so replaying it verbatim would just make the server reject
+			// the encapsulation.
+			keyShares = append(keyShares, tls.KeyShare{Group: curveID})
+			continue
+		case tls.X25519:
+			curve := ecdh.X25519()
+			priv, err := curve.GenerateKey(rand.Reader)
+			if err == nil {
+				data = priv.PublicKey().Bytes()
+			}
+		case tls.CurveP256:
+			curve := ecdh.P256()
+			priv, err := curve.GenerateKey(rand.Reader)
+			if err == nil {
+				data = priv.PublicKey().Bytes()
+			}
+		case tls.CurveP384:
+			curve := ecdh.P384()
+			priv, err := curve.GenerateKey(rand.Reader)
+			if err == nil {
+				data = priv.PublicKey().Bytes()
+			}
+		default:
+			data, _ = hex.DecodeString(s.DataHex)
+			if len(data) == 0 {
+				continue // don't send an empty KeyShare
+			}
+		}
+
+		keyShares = append(keyShares, tls.KeyShare{Group: curveID, Data: data})
+	}
+	return keyShares, nil
+}
+
+// ConfigFromSpec converts a parsed ClientHelloSpec (e.g. from
+// tls.Fingerprinter.RawClientHello) back into our Config/ExtensionConfig
+// schema, so a captured ClientHello round-trips through the client unchanged.
+func ConfigFromSpec(spec *tls.ClientHelloSpec) (Config, error) {
+	config := Config{
+		CipherSuites:       spec.CipherSuites,
+		CompressionMethods: spec.CompressionMethods,
+		TLSVersMin:         spec.TLSVersMin,
+		TLSVersMax:         spec.TLSVersMax,
+	}
+
+	for _, e := range spec.Extensions {
+		extCfg, err := extensionConfigFromTLS(e)
+		if err != nil {
+			return config, err
+		}
+		config.Extensions = append(config.Extensions, extCfg)
+	}
+
+	return config, nil
+}
+
+func extensionConfigFromTLS(e tls.TLSExtension) (ExtensionConfig, error) {
+	marshal := func(typ string, v interface{}) (ExtensionConfig, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ExtensionConfig{}, fmt.Errorf("marshal %s extension: %w", typ, err)
+		}
+		return ExtensionConfig{Type: typ, Data: data}, nil
+	}
+
+	switch ext := e.(type) {
+	case *tls.SNIExtension:
+		return ExtensionConfig{Type: "SNI"}, nil
+	case *tls.StatusRequestExtension:
+		return ExtensionConfig{Type: "StatusRequest"}, nil
+	case *tls.SupportedCurvesExtension:
+		return marshal("SupportedCurves", ext.Curves)
+	case *tls.SupportedPointsExtension:
+		return marshal("SupportedPoints", ext.SupportedPoints)
+	case *tls.SignatureAlgorithmsExtension:
+		return marshal("SignatureAlgorithms", ext.SupportedSignatureAlgorithms)
+	case *tls.ALPNExtension:
+		return marshal("ALPN", ext.AlpnProtocols)
+	case *tls.ExtendedMasterSecretExtension:
+		return ExtensionConfig{Type: "ExtendedMasterSecret"}, nil
+	case *tls.SessionTicketExtension:
+		return ExtensionConfig{Type: "SessionTicket"}, nil
+	case *tls.SupportedVersionsExtension:
+		return marshal("SupportedVersions", ext.Versions)
+	case *tls.PSKKeyExchangeModesExtension:
+		return marshal("PSKKeyExchangeModes", ext.Modes)
+	case *tls.KeyShareExtension:
+		shares := make([]KeyShare, len(ext.KeyShares))
+		for i, ks := range ext.KeyShares {
+			shares[i] = KeyShare{Group: uint16(ks.Group), DataHex: hex.EncodeToString(ks.Data)}
+		}
+		return marshal("KeyShare", shares)
+	case *tls.SCTExtension:
+		return ExtensionConfig{Type: "SCT"}, nil
+	case *tls.RenegotiationInfoExtension:
+		return marshal("RenegotiationInfo", uint8(ext.Renegotiation))
+	case *tls.ApplicationSettingsExtension:
+		return marshal("ApplicationSettings", ext.SupportedProtocols)
+	case *tls.UtlsCompressCertExtension:
+		return marshal("CompressCertificate", ext.Algorithms)
+	case *tls.UtlsGREASEExtension:
+		return marshal("GREASE", ext.Value)
+	case *tls.UtlsPaddingExtension:
+		paddingLen, willPad := ext.GetPaddingLen(0)
+		return marshal("Padding", PaddingConfig{PaddingLen: paddingLen, WillPad: willPad})
+	case *tls.CookieExtension:
+		return marshal("Cookie", CookieConfig{DataHex: hex.EncodeToString(ext.Cookie)})
+	case *tls.UtlsPreSharedKeyExtension:
+		// Captured for replay purposes only: a fresh binder can't be
+		// recomputed without the original session secret, so this is only
+		// good for reproducing the wire bytes, not for real resumption.
+		identities := make([]PSKIdentity, len(ext.Identities))
+		for i, id := range ext.Identities {
+			identities[i] = PSKIdentity{LabelHex: hex.EncodeToString(id.Label), ObfuscatedTicketAge: id.ObfuscatedTicketAge}
+		}
+		binders := make([]string, len(ext.Binders))
+		for i, b := range ext.Binders {
+			binders[i] = hex.EncodeToString(b)
+		}
+		return marshal("PreSharedKey", PSKConfig{Identities: identities, BindersHex: binders})
+	case *tls.GenericExtension:
+		return marshal("Generic", GenericExtensionConfig{ID: ext.Id, DataHex: hex.EncodeToString(ext.Data)})
+	default:
+		return ExtensionConfig{}, fmt.Errorf("unsupported extension type %T", e)
+	}
+}
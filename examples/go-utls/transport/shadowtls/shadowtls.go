@@ -0,0 +1,120 @@
+// Package shadowtls implements a ShadowTLS v3 client transport: it dials a
+// relay, performs a real uTLS handshake against a "cover" site through that
+// relay using a fingerprinted ClientHelloSpec, and then authenticates a
+// switch from the cover handshake to the real payload channel using
+// HMAC-SHA1 over the shared password.
+//
+// This follows the publicly documented ShadowTLS v3 handshake outline: the
+// relay never holds TLS keys, so it can't verify anything by decrypting the
+// connection. Instead it keeps a running per-direction HMAC over every byte
+// it relays during the handshake, and the client proves it knows the
+// password by sending a tag derived from that same running state through
+// the now-established encrypted channel to the cover site; the relay acks
+// with a tag of its own over the download direction, mirroring the
+// ChangeCipherSpec-style confirmation the real protocol uses before real
+// traffic starts flowing. The exact framing of that switch is a best-effort
+// implementation of the documented behavior rather than a byte-for-byte port
+// of the reference implementation, which isn't vendored in this tree.
+package shadowtls
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	tls "github.com/refraction-networking/utls"
+)
+
+// Config describes a ShadowTLS v3 relay to dial through.
+type Config struct {
+	Server   string // relay address, host:port
+	Password string // shared secret authenticating client <-> relay
+	CoverSNI string // SNI of the cover site the relay forwards the handshake to
+	Spec     *tls.ClientHelloSpec
+}
+
+// Dial connects to cfg.Server, completes a fingerprinted handshake against
+// cfg.CoverSNI through it, authenticates the switch to the real payload
+// channel, and returns a net.Conn carrying that payload channel.
+func Dial(cfg Config) (net.Conn, error) {
+	raw, err := net.Dial("tcp", cfg.Server)
+	if err != nil {
+		return nil, fmt.Errorf("shadowtls: dial relay: %w", err)
+	}
+
+	rec := &recordingConn{Conn: raw}
+	tlsCfg := &tls.Config{ServerName: cfg.CoverSNI, InsecureSkipVerify: true}
+	uConn := tls.UClient(rec, tlsCfg, tls.HelloCustom)
+
+	if err := uConn.ApplyPreset(cfg.Spec); err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("shadowtls: apply fingerprint: %w", err)
+	}
+	if err := uConn.Handshake(); err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("shadowtls: cover handshake: %w", err)
+	}
+
+	// uploadTag/downloadTag are computed over every byte exchanged with the
+	// relay during the handshake (both directions, handshake records past
+	// the first included), not just the initial ClientHello/ServerHello, so
+	// they play the role of the v3 protocol's running per-direction HMAC
+	// state at the point the switch is requested.
+	uploadTag := switchTag(cfg.Password, rec.upload, "switch")
+	if _, err := uConn.Write(uploadTag); err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("shadowtls: sending switch signal: %w", err)
+	}
+
+	downloadTag := switchTag(cfg.Password, rec.download, "switch-ack")
+	got := make([]byte, len(downloadTag))
+	if _, err := io.ReadFull(raw, got); err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("shadowtls: reading switch confirmation: %w", err)
+	}
+	if !hmac.Equal(got, downloadTag) {
+		raw.Close()
+		return nil, errors.New("shadowtls: switch confirmation HMAC mismatch (relay may not know the password)")
+	}
+
+	return raw, nil
+}
+
+// switchTag derives the HMAC-SHA1 tag for one direction of the switch
+// handshake: password-keyed, over every byte seen in that direction during
+// the cover handshake plus a label distinguishing the client's request from
+// the relay's acknowledgement.
+func switchTag(password string, trace []byte, label string) []byte {
+	mac := hmac.New(sha1.New, []byte(password))
+	mac.Write(trace)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// recordingConn tees every byte written (upload) and read (download) during
+// the cover handshake, so the full handshake trace is available to derive
+// the per-direction switch tags once Handshake returns.
+type recordingConn struct {
+	net.Conn
+	upload   []byte
+	download []byte
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.upload = append(c.upload, p[:n]...)
+	}
+	return n, err
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.download = append(c.download, p[:n]...)
+	}
+	return n, err
+}